@@ -0,0 +1,157 @@
+package sasl
+
+import "fmt"
+
+// Mechanism names. NewClient builds Clients for PLAIN and GSSAPI only;
+// it returns an error for DIGEST-MD5 and SCRAM-SHA-256.
+const (
+	MechPlain       = "PLAIN"
+	MechGSSAPI      = "GSSAPI"
+	MechDigestMD5   = "DIGEST-MD5"
+	MechScramSHA256 = "SCRAM-SHA-256"
+)
+
+// QOP is a SASL quality-of-protection level, as negotiated by GSSAPI,
+// DIGEST-MD5 and SCRAM-SHA-256. Mechanisms without a security layer
+// (e.g. PLAIN) always report QOPAuth.
+type QOP byte
+
+// QOP levels, matching the bitmask used in the RFC 4752 security layer
+// negotiation byte.
+const (
+	QOPAuth     QOP = 1 << 0 // authentication only
+	QOPAuthInt  QOP = 1 << 1 // authentication + integrity
+	QOPAuthConf QOP = 1 << 2 // authentication + integrity + confidentiality
+)
+
+// DefaultMaxFrameSize bounds negotiation and data frames when
+// Options.MaxFrameSize is left at zero. It is kept within 0xFFFFFF so
+// it also fits unmodified into the 3-byte maxbuf field GSSAPI's RFC
+// 4752 security layer negotiation carries it in.
+const DefaultMaxFrameSize = 4 * 1024 * 1024
+
+// Options configures SASL negotiation and is shared by every Client
+// NewClient can build.
+type Options struct {
+	// Mechanisms lists the mechanisms to offer the server, in
+	// preference order. Start tries each in turn until one can be
+	// satisfied by the credentials in Options. Defaults to
+	// []string{MechPlain}.
+	Mechanisms []string
+
+	// PLAIN credentials.
+	Username string
+	Password string
+
+	// GSSAPI credentials. Service and Host identify the server
+	// principal (service/host); one of Keytab or CCache must be set to
+	// authenticate as the client principal, keytab taking precedence
+	// when both are present.
+	Service string
+	Host    string
+	Realm   string
+	Keytab  string
+	CCache  string
+
+	// QOP lists the quality-of-protection levels this client will
+	// offer during the GSSAPI/DIGEST-MD5/SCRAM-SHA-256 security layer
+	// negotiation, in preference order. Defaults to []QOP{QOPAuth}.
+	QOP []QOP
+
+	// MaxFrameSize bounds every frame this transport reads from the
+	// peer, negotiation or data. Zero selects DefaultMaxFrameSize.
+	MaxFrameSize uint32
+
+	// Logger receives structured events for each negotiation step.
+	// Defaults to a no-op logger.
+	Logger Logger
+}
+
+func (o *Options) mechanisms() []string {
+	if o == nil || len(o.Mechanisms) == 0 {
+		return []string{MechPlain}
+	}
+	return o.Mechanisms
+}
+
+func (o *Options) qop() []QOP {
+	if o == nil || len(o.QOP) == 0 {
+		return []QOP{QOPAuth}
+	}
+	return o.QOP
+}
+
+func (o *Options) maxFrameSize() uint32 {
+	if o == nil || o.MaxFrameSize == 0 {
+		return DefaultMaxFrameSize
+	}
+	return o.MaxFrameSize
+}
+
+func (o *Options) logger() Logger {
+	if o == nil || o.Logger == nil {
+		return noopLogger{}
+	}
+	return o.Logger
+}
+
+// Client implements one SASL mechanism's negotiation handshake and,
+// once negotiation selects a security layer, the wrap/unwrap framing
+// that protects subsequent traffic.
+type Client interface {
+	// Start begins negotiation. mechanisms is the preference-ordered
+	// list the transport offers the server; Start picks the first one
+	// it can satisfy. It returns the chosen mechanism name, the
+	// initial response to send (nil if the mechanism has none), and
+	// whether negotiation is already complete.
+	Start(mechanisms []string) (mech string, initial []byte, complete bool, err error)
+
+	// Step advances negotiation with the server's challenge, returning
+	// the next response to send and whether negotiation is complete.
+	Step(challenge []byte) (response []byte, complete bool, err error)
+
+	// QOP reports the quality of protection negotiated. It is only
+	// meaningful once Step has reported completion.
+	QOP() QOP
+
+	// Wrap applies the negotiated security layer to plaintext before
+	// it is framed and sent. Mechanisms negotiated at QOPAuth return
+	// plaintext unchanged.
+	Wrap(plaintext []byte) ([]byte, error)
+
+	// Unwrap reverses Wrap.
+	Unwrap(ciphertext []byte) ([]byte, error)
+
+	// Free releases any resources held by the client, such as a
+	// Kerberos credentials cache.
+	Free()
+}
+
+// NewClient builds a Client for the first mechanism in opts.Mechanisms
+// that opts carries enough configuration to attempt. A mechanism is
+// only skipped in favor of the next one when it isn't configured at
+// all (e.g. GSSAPI requested without credentials); once NewClient
+// commits to building a mechanism, a construction error is returned
+// rather than silently downgrading to a different mechanism. The
+// returned Client still needs Start to begin negotiation.
+func NewClient(opts *Options) (Client, error) {
+	for _, mech := range opts.mechanisms() {
+		switch mech {
+		case MechGSSAPI:
+			if opts.Service == "" || (opts.Keytab == "" && opts.CCache == "") {
+				opts.logger().Warn("sasl: GSSAPI requested but not configured, trying next mechanism", "service", opts.Service)
+				continue
+			}
+			c, err := NewGSSAPIClient(opts.Service, opts.Realm, opts)
+			if err != nil {
+				return nil, fmt.Errorf("sasl: building GSSAPI client: %v", err)
+			}
+			return c, nil
+		case MechPlain:
+			return newPlainClient(opts), nil
+		default:
+			return nil, fmt.Errorf("sasl: mechanism %q has no Client implementation", mech)
+		}
+	}
+	return nil, fmt.Errorf("sasl: no usable mechanism in %v", opts.mechanisms())
+}
@@ -0,0 +1,57 @@
+package sasl
+
+import "testing"
+
+func TestNewClientPlain(t *testing.T) {
+	c, err := NewClient(&Options{Username: "alice", Password: "s3cret"})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	if _, ok := c.(*plainClient); !ok {
+		t.Fatalf("got %T, want *plainClient", c)
+	}
+}
+
+func TestNewClientFallsBackFromUnconfiguredGSSAPIToPlain(t *testing.T) {
+	c, err := NewClient(&Options{
+		Mechanisms: []string{MechGSSAPI, MechPlain},
+		Username:   "alice",
+		Password:   "s3cret",
+	})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	if _, ok := c.(*plainClient); !ok {
+		t.Fatalf("got %T, want *plainClient", c)
+	}
+}
+
+func TestNewClientWarnsWhenSkippingUnconfiguredGSSAPI(t *testing.T) {
+	log := &recordingLogger{}
+	_, err := NewClient(&Options{
+		Mechanisms: []string{MechGSSAPI, MechPlain},
+		Username:   "alice",
+		Password:   "s3cret",
+		Logger:     log,
+	})
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	if len(log.warnings) != 1 {
+		t.Fatalf("got %d warnings, want 1: %v", len(log.warnings), log.warnings)
+	}
+}
+
+func TestNewClientErrorsWhenNoMechanismCanBeSatisfied(t *testing.T) {
+	_, err := NewClient(&Options{Mechanisms: []string{MechGSSAPI}})
+	if err == nil {
+		t.Fatal("expected an error when GSSAPI is requested without credentials and nothing else is offered")
+	}
+}
+
+func TestNewClientErrorsOnUnimplementedMechanism(t *testing.T) {
+	_, err := NewClient(&Options{Mechanisms: []string{MechDigestMD5}})
+	if err == nil {
+		t.Fatal("expected an error for a mechanism with no Client implementation")
+	}
+}
@@ -0,0 +1,74 @@
+package sasl
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// encodeNegotiationFrame encodes a 1-byte status followed by a 4-byte
+// big-endian length prefix and body, the framing used throughout SASL
+// negotiation.
+func encodeNegotiationFrame(status Status, body []byte) []byte {
+	frame := make([]byte, 0, 5+len(body))
+	frame = append(frame, byte(status))
+	frame = appendUint32(frame, uint32(len(body)))
+	frame = append(frame, body...)
+	return frame
+}
+
+// decodeNegotiationFrame reads one status-prefixed negotiation frame
+// from r, rejecting bodies larger than maxFrameSize before allocating
+// for them.
+func decodeNegotiationFrame(r io.Reader, maxFrameSize uint32) (Status, []byte, error) {
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return 0, nil, err
+	}
+
+	status := Status(header[0])
+	l := binary.BigEndian.Uint32(header[1:])
+	if l > maxFrameSize {
+		return 0, nil, fmt.Errorf("sasl: negotiation frame of %d bytes exceeds max frame size %d", l, maxFrameSize)
+	}
+
+	body := make([]byte, l)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+	return status, body, nil
+}
+
+// encodeDataFrame encodes a 4-byte big-endian length prefix and body,
+// the framing used for data exchanged once negotiation completes.
+func encodeDataFrame(body []byte) []byte {
+	frame := make([]byte, 0, 4+len(body))
+	frame = appendUint32(frame, uint32(len(body)))
+	frame = append(frame, body...)
+	return frame
+}
+
+// decodeDataFrame reads one length-prefixed data frame from r,
+// rejecting bodies larger than maxFrameSize before allocating for
+// them.
+func decodeDataFrame(r io.Reader, maxFrameSize uint32) ([]byte, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+
+	l := binary.BigEndian.Uint32(header)
+	if l > maxFrameSize {
+		return nil, fmt.Errorf("sasl: data frame of %d bytes exceeds max frame size %d", l, maxFrameSize)
+	}
+
+	body := make([]byte, l)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
@@ -0,0 +1,131 @@
+package sasl
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"math/rand"
+	"testing"
+)
+
+// pipeTransport is a minimal thrift.TTransport backed by an io.Pipe. A
+// bytes.Buffer always returns everything that's been written in one
+// Read; a pipe only returns what the writer has flushed so far, so it
+// reproduces the short reads a real socket can produce.
+type pipeTransport struct {
+	r *io.PipeReader
+	w *io.PipeWriter
+}
+
+func newPipeTransport() *pipeTransport {
+	r, w := io.Pipe()
+	return &pipeTransport{r: r, w: w}
+}
+
+func (p *pipeTransport) Read(b []byte) (int, error)  { return p.r.Read(b) }
+func (p *pipeTransport) Write(b []byte) (int, error) { return p.w.Write(b) }
+func (p *pipeTransport) Close() error {
+	p.w.Close()
+	return p.r.Close()
+}
+func (p *pipeTransport) Flush(context.Context) error { return nil }
+func (p *pipeTransport) Open() error                 { return nil }
+func (p *pipeTransport) IsOpen() bool                { return true }
+func (p *pipeTransport) RemainingBytes() uint64      { return 0 }
+
+// writeInRandomChunks writes b to w in randomly sized pieces, as a real
+// socket might deliver a single logical write across several reads.
+func writeInRandomChunks(w io.WriteCloser, b []byte) {
+	defer w.Close()
+	for len(b) > 0 {
+		n := 1 + rand.Intn(len(b))
+		w.Write(b[:n])
+		b = b[n:]
+	}
+}
+
+func FuzzSaslFraming(f *testing.F) {
+	f.Add(byte(StatusOK), []byte("hello"))
+	f.Add(byte(StatusComplete), []byte{})
+	f.Add(byte(StatusBad), bytes.Repeat([]byte{0xff}, 512))
+
+	f.Fuzz(func(t *testing.T, status byte, body []byte) {
+		negEncoded := encodeNegotiationFrame(Status(status), body)
+		negStatus, negBody, err := decodeNegotiationFrame(bytes.NewReader(negEncoded), DefaultMaxFrameSize)
+		if err != nil {
+			t.Fatalf("negotiation frame round-trip failed: %v", err)
+		}
+		if negStatus != Status(status) || !bytes.Equal(negBody, body) {
+			t.Fatalf("negotiation frame round-trip mismatch: got (%d, %q), want (%d, %q)", negStatus, negBody, status, body)
+		}
+
+		dataEncoded := encodeDataFrame(body)
+		dataBody, err := decodeDataFrame(bytes.NewReader(dataEncoded), DefaultMaxFrameSize)
+		if err != nil {
+			t.Fatalf("data frame round-trip failed: %v", err)
+		}
+		if !bytes.Equal(dataBody, body) {
+			t.Fatalf("data frame round-trip mismatch: got %q, want %q", dataBody, body)
+		}
+	})
+}
+
+func TestDecodeFrameSurvivesShortReads(t *testing.T) {
+	for i := 0; i < 1000; i++ {
+		body := make([]byte, rand.Intn(256))
+		rand.Read(body)
+		encoded := encodeNegotiationFrame(StatusOK, body)
+
+		trans := newPipeTransport()
+		go writeInRandomChunks(trans.w, encoded)
+
+		status, decoded, err := decodeNegotiationFrame(trans, DefaultMaxFrameSize)
+		trans.Close()
+		if err != nil {
+			t.Fatalf("iteration %d: decode failed: %v", i, err)
+		}
+		if status != StatusOK || !bytes.Equal(decoded, body) {
+			t.Fatalf("iteration %d: decoded frame does not match input", i)
+		}
+	}
+}
+
+func TestDecodeNegotiationFrameRejectsOversizeLength(t *testing.T) {
+	trans := newPipeTransport()
+	defer trans.Close()
+
+	header := make([]byte, 5)
+	header[0] = byte(StatusOK)
+	binary.BigEndian.PutUint32(header[1:], 1<<20)
+	go trans.w.Write(header)
+
+	if _, _, err := decodeNegotiationFrame(trans, 1024); err == nil {
+		t.Fatal("expected oversize negotiation frame length to be rejected")
+	}
+}
+
+func TestDecodeDataFrameRejectsOversizeLength(t *testing.T) {
+	trans := newPipeTransport()
+	defer trans.Close()
+
+	header := make([]byte, 4)
+	binary.BigEndian.PutUint32(header, 1<<20)
+	go trans.w.Write(header)
+
+	if _, err := decodeDataFrame(trans, 1024); err == nil {
+		t.Fatal("expected oversize data frame length to be rejected")
+	}
+}
+
+func TestDecodeNegotiationFrameTruncatedHeader(t *testing.T) {
+	trans := newPipeTransport()
+	go func() {
+		trans.w.Write([]byte{byte(StatusOK), 0, 0})
+		trans.w.Close()
+	}()
+
+	if _, _, err := decodeNegotiationFrame(trans, DefaultMaxFrameSize); err == nil {
+		t.Fatal("expected truncated header to surface an error instead of a short read")
+	}
+}
@@ -0,0 +1,294 @@
+package sasl
+
+import (
+	"encoding/binary"
+	"fmt"
+	"time"
+
+	krb5client "github.com/jcmturner/gokrb5/v8/client"
+	"github.com/jcmturner/gokrb5/v8/config"
+	"github.com/jcmturner/gokrb5/v8/credentials"
+	"github.com/jcmturner/gokrb5/v8/crypto"
+	"github.com/jcmturner/gokrb5/v8/gssapi"
+	"github.com/jcmturner/gokrb5/v8/iana/keyusage"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/jcmturner/gokrb5/v8/messages"
+	"github.com/jcmturner/gokrb5/v8/types"
+)
+
+// gssapiPhase tracks where a gssapiClient is within the GSSAPI SASL
+// mechanism: first Kerberos context establishment (AP-REQ/AP-REP), then
+// the RFC 4752 security layer negotiation.
+type gssapiPhase int
+
+const (
+	phaseContext gssapiPhase = iota
+	phaseSecurityLayer
+	phaseDone
+)
+
+// maxRFC4752Buf is the largest value that fits in the 3-byte maxbuf
+// field RFC 4752's security layer negotiation uses.
+const maxRFC4752Buf = 0xFFFFFF
+
+// gssapiClient implements Client for the GSSAPI mechanism on top of a
+// Kerberos 5 context obtained via gokrb5.
+type gssapiClient struct {
+	opts *Options
+	krb  *krb5client.Client
+	spn  string
+
+	key    types.EncryptionKey
+	phase  gssapiPhase
+	qop    QOP
+	maxBuf uint32
+
+	// authTime/authCusec are the authenticator timestamp initSecContext
+	// sent in the AP-REQ; Step checks the server's AP-REP echoes them
+	// back before trusting it.
+	authTime  time.Time
+	authCusec int
+}
+
+// NewGSSAPIClient builds a Client that authenticates to service/host
+// over Kerberos, using whichever of opts.Keytab or opts.CCache is set
+// (keytab taking precedence when both are present).
+func NewGSSAPIClient(service, realm string, opts *Options) (Client, error) {
+	cfg, err := config.Load("/etc/krb5.conf")
+	if err != nil {
+		cfg = config.New()
+		cfg.LibDefaults.DefaultRealm = realm
+	}
+
+	var krb *krb5client.Client
+	switch {
+	case opts.Keytab != "":
+		kt, err := keytab.Load(opts.Keytab)
+		if err != nil {
+			return nil, fmt.Errorf("sasl: loading keytab %s: %v", opts.Keytab, err)
+		}
+		krb = krb5client.NewWithKeytab(opts.Username, realm, kt, cfg, krb5client.DisablePAFXFAST(true))
+	case opts.CCache != "":
+		cc, err := credentials.LoadCCache(opts.CCache)
+		if err != nil {
+			return nil, fmt.Errorf("sasl: loading credentials cache %s: %v", opts.CCache, err)
+		}
+		krb, err = krb5client.NewFromCCache(cc, cfg, krb5client.DisablePAFXFAST(true))
+		if err != nil {
+			return nil, fmt.Errorf("sasl: building client from credentials cache %s: %v", opts.CCache, err)
+		}
+	default:
+		return nil, fmt.Errorf("sasl: GSSAPI requires Options.Keytab or Options.CCache")
+	}
+
+	if err := krb.Login(); err != nil {
+		return nil, fmt.Errorf("sasl: kerberos login: %v", err)
+	}
+
+	spn := service
+	if opts.Host != "" {
+		spn = service + "/" + opts.Host
+	}
+
+	return &gssapiClient{opts: opts, krb: krb, spn: spn, phase: phaseContext}, nil
+}
+
+func (c *gssapiClient) Start(mechanisms []string) (string, []byte, bool, error) {
+	offered := false
+	for _, m := range mechanisms {
+		if m == MechGSSAPI {
+			offered = true
+		}
+	}
+	if !offered {
+		return "", nil, false, fmt.Errorf("sasl: GSSAPI not offered by %v", mechanisms)
+	}
+
+	token, err := c.initSecContext()
+	if err != nil {
+		return "", nil, false, err
+	}
+	return MechGSSAPI, token, false, nil
+}
+
+// initSecContext obtains a service ticket for c.spn and wraps it in an
+// AP-REQ, the only token the client sends while establishing the
+// Kerberos context; Step decrypts and verifies the server's AP-REP
+// against the authenticator built here.
+func (c *gssapiClient) initSecContext() ([]byte, error) {
+	tkt, key, err := c.krb.GetServiceTicket(c.spn)
+	if err != nil {
+		return nil, fmt.Errorf("sasl: obtaining service ticket for %s: %v", c.spn, err)
+	}
+	c.key = key
+
+	auth, err := types.NewAuthenticator(c.krb.Credentials.Domain(), c.krb.Credentials.CName())
+	if err != nil {
+		return nil, fmt.Errorf("sasl: building authenticator: %v", err)
+	}
+	c.authTime = auth.CTime
+	c.authCusec = auth.Cusec
+
+	apReq, err := messages.NewAPReq(tkt, key, auth)
+	if err != nil {
+		return nil, fmt.Errorf("sasl: building AP-REQ: %v", err)
+	}
+
+	return apReq.Marshal()
+}
+
+// verifyAPRep decrypts apRep.EncPart with the session key and checks
+// that the ctime/cusec it carries match the authenticator
+// initSecContext sent, per RFC 4120 S3.2.5. Unmarshal alone only
+// parses the ASN.1 structure; without this, any well-formed AP-REP
+// would be accepted regardless of whether it came from a party that
+// actually held the session key.
+func (c *gssapiClient) verifyAPRep(apRep messages.APRep) error {
+	b, err := crypto.DecryptEncPart(apRep.EncPart, c.key, keyusage.AP_REP_ENCPART)
+	if err != nil {
+		return fmt.Errorf("decrypting EncPart: %v", err)
+	}
+
+	var encPart messages.EncAPRepPart
+	if err := encPart.Unmarshal(b); err != nil {
+		return fmt.Errorf("parsing EncAPRepPart: %v", err)
+	}
+
+	if !encPart.CTime.Equal(c.authTime) || encPart.Cusec != c.authCusec {
+		return fmt.Errorf("ctime/cusec mismatch: server echoed (%v, %d), client sent (%v, %d)",
+			encPart.CTime, encPart.Cusec, c.authTime, c.authCusec)
+	}
+
+	return nil
+}
+
+func (c *gssapiClient) Step(challenge []byte) ([]byte, bool, error) {
+	switch c.phase {
+	case phaseContext:
+		var apRep messages.APRep
+		if err := apRep.Unmarshal(challenge); err != nil {
+			return nil, false, fmt.Errorf("sasl: parsing AP-REP: %v", err)
+		}
+		if err := c.verifyAPRep(apRep); err != nil {
+			return nil, false, fmt.Errorf("sasl: verifying AP-REP: %v", err)
+		}
+		c.phase = phaseSecurityLayer
+		return nil, false, nil
+
+	case phaseSecurityLayer:
+		resp, err := c.negotiateSecurityLayer(challenge)
+		if err != nil {
+			return nil, false, err
+		}
+		c.phase = phaseDone
+		return resp, true, nil
+
+	default:
+		return nil, false, fmt.Errorf("sasl: unexpected challenge after negotiation complete")
+	}
+}
+
+// negotiateSecurityLayer implements the final RFC 4752 round: the
+// server sends a GSS-wrapped byte of supported QOP flags followed by
+// its max receive buffer size (3 bytes, big-endian); the client replies
+// in kind with the QOP it selects and the buffer size it will accept.
+func (c *gssapiClient) negotiateSecurityLayer(wrapped []byte) ([]byte, error) {
+	raw, err := c.unwrap(wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("sasl: unwrapping security layer offer: %v", err)
+	}
+	if len(raw) < 4 {
+		return nil, fmt.Errorf("sasl: security layer offer too short: %d bytes", len(raw))
+	}
+
+	serverQOP := QOP(raw[0])
+	serverMaxBuf := binary.BigEndian.Uint32(append([]byte{0}, raw[1:4]...))
+
+	qop, maxBuf, resp, err := selectSecurityLayer(serverQOP, serverMaxBuf, c.opts)
+	if err != nil {
+		return nil, err
+	}
+	if preferred := c.opts.qop(); len(preferred) > 0 && qop != preferred[0] {
+		c.opts.logger().Warn("sasl: server did not support preferred QOP, negotiated a weaker one", "preferred", preferred[0], "negotiated", qop)
+	}
+	c.qop = qop
+	c.maxBuf = maxBuf
+
+	return c.wrap(resp)
+}
+
+// selectSecurityLayer picks the highest-preference QOP the client and
+// server both support and the maxbuf the client will advertise back,
+// clamped to maxRFC4752Buf so it always fits the 3-byte field the
+// response encodes it in. Per RFC 4752 S3.3, it is an error for
+// serverQOP to share no bit with opts.qop() — the negotiation fails
+// rather than guessing a level the server never offered. Split out
+// from negotiateSecurityLayer so the byte-packing can be table-tested
+// without a live Kerberos context.
+func selectSecurityLayer(serverQOP QOP, serverMaxBuf uint32, opts *Options) (QOP, uint32, []byte, error) {
+	var qop QOP
+	matched := false
+	for _, want := range opts.qop() {
+		if serverQOP&want != 0 {
+			qop = want
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		return 0, 0, nil, fmt.Errorf("sasl: server does not support any requested QOP (offered %#x, wanted %v)", byte(serverQOP), opts.qop())
+	}
+
+	maxBuf := opts.maxFrameSize()
+	if serverMaxBuf < maxBuf {
+		maxBuf = serverMaxBuf
+	}
+	if maxBuf > maxRFC4752Buf {
+		maxBuf = maxRFC4752Buf
+	}
+
+	resp := []byte{byte(qop), byte(maxBuf >> 16), byte(maxBuf >> 8), byte(maxBuf)}
+	return qop, maxBuf, resp, nil
+}
+
+func (c *gssapiClient) QOP() QOP { return c.qop }
+
+func (c *gssapiClient) Wrap(plaintext []byte) ([]byte, error) {
+	if c.qop == QOPAuth {
+		return plaintext, nil
+	}
+	return c.wrap(plaintext)
+}
+
+func (c *gssapiClient) Unwrap(ciphertext []byte) ([]byte, error) {
+	if c.qop == QOPAuth {
+		return ciphertext, nil
+	}
+	if c.maxBuf != 0 && uint32(len(ciphertext)) > c.maxBuf+64 {
+		return nil, fmt.Errorf("sasl: wrapped frame of %d bytes exceeds negotiated maxbuf %d", len(ciphertext), c.maxBuf)
+	}
+	return c.unwrap(ciphertext)
+}
+
+func (c *gssapiClient) wrap(plaintext []byte) ([]byte, error) {
+	tok, err := gssapi.NewInitiatorWrapToken(plaintext, c.key)
+	if err != nil {
+		return nil, err
+	}
+	return tok.Marshal()
+}
+
+func (c *gssapiClient) unwrap(ciphertext []byte) ([]byte, error) {
+	var tok gssapi.WrapToken
+	if err := tok.Unmarshal(ciphertext, true); err != nil {
+		return nil, err
+	}
+	if ok, err := tok.Verify(c.key, keyusage.GSSAPI_ACCEPTOR_SEAL); !ok {
+		return nil, fmt.Errorf("sasl: wrap token checksum verification failed: %v", err)
+	}
+	return tok.Payload, nil
+}
+
+func (c *gssapiClient) Free() {
+	c.krb.Destroy()
+}
@@ -0,0 +1,85 @@
+package sasl
+
+import "testing"
+
+func TestSelectSecurityLayer(t *testing.T) {
+	tests := []struct {
+		name         string
+		serverQOP    QOP
+		serverMaxBuf uint32
+		opts         *Options
+		wantQOP      QOP
+		wantMaxBuf   uint32
+		wantErr      bool
+	}{
+		{
+			name:         "default MaxFrameSize caps a larger server maxbuf",
+			serverQOP:    QOPAuth,
+			serverMaxBuf: 1 << 30,
+			opts:         &Options{},
+			wantQOP:      QOPAuth,
+			wantMaxBuf:   DefaultMaxFrameSize,
+		},
+		{
+			name:         "server maxbuf smaller than ours wins",
+			serverQOP:    QOPAuth,
+			serverMaxBuf: 1024,
+			opts:         &Options{},
+			wantQOP:      QOPAuth,
+			wantMaxBuf:   1024,
+		},
+		{
+			name:         "configured MaxFrameSize above the 24-bit field is clamped",
+			serverQOP:    QOPAuth,
+			serverMaxBuf: 1 << 30,
+			opts:         &Options{MaxFrameSize: 1 << 28},
+			wantQOP:      QOPAuth,
+			wantMaxBuf:   maxRFC4752Buf,
+		},
+		{
+			name:         "preferred QOP is picked when the server supports it",
+			serverQOP:    QOPAuth | QOPAuthConf,
+			serverMaxBuf: 4096,
+			opts:         &Options{QOP: []QOP{QOPAuthConf, QOPAuth}},
+			wantQOP:      QOPAuthConf,
+			wantMaxBuf:   4096,
+		},
+		{
+			name:         "errors when the server offers nothing we want",
+			serverQOP:    QOPAuthInt,
+			serverMaxBuf: 4096,
+			opts:         &Options{QOP: []QOP{QOPAuthConf}},
+			wantErr:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			qop, maxBuf, resp, err := selectSecurityLayer(tt.serverQOP, tt.serverMaxBuf, tt.opts)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error when no requested QOP is in the server's offer")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("selectSecurityLayer: %v", err)
+			}
+
+			if qop != tt.wantQOP {
+				t.Errorf("qop = %d, want %d", qop, tt.wantQOP)
+			}
+			if maxBuf != tt.wantMaxBuf {
+				t.Errorf("maxBuf = %d, want %d", maxBuf, tt.wantMaxBuf)
+			}
+			if maxBuf > maxRFC4752Buf {
+				t.Fatalf("maxBuf %d does not fit the 3-byte maxbuf field", maxBuf)
+			}
+
+			wantResp := []byte{byte(qop), byte(maxBuf >> 16), byte(maxBuf >> 8), byte(maxBuf)}
+			if len(resp) != 4 || string(resp) != string(wantResp) {
+				t.Errorf("resp = %v, want %v", resp, wantResp)
+			}
+		})
+	}
+}
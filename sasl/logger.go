@@ -0,0 +1,38 @@
+package sasl
+
+import "log/slog"
+
+// Logger receives structured events describing SASL negotiation
+// progress, in the log15/slog style of alternating key/value pairs.
+// Implementations must be safe for concurrent use.
+type Logger interface {
+	Debug(msg string, kv ...interface{})
+	Info(msg string, kv ...interface{})
+	Warn(msg string, kv ...interface{})
+	Error(msg string, kv ...interface{})
+}
+
+// noopLogger discards every event. It is the default Logger when
+// Options.Logger is unset.
+type noopLogger struct{}
+
+func (noopLogger) Debug(string, ...interface{}) {}
+func (noopLogger) Info(string, ...interface{})  {}
+func (noopLogger) Warn(string, ...interface{})  {}
+func (noopLogger) Error(string, ...interface{}) {}
+
+// slogLogger adapts a *slog.Logger to Logger.
+type slogLogger struct {
+	l *slog.Logger
+}
+
+// NewSlogLogger adapts l to Logger, letting callers route SASL
+// negotiation events into an existing log/slog pipeline.
+func NewSlogLogger(l *slog.Logger) Logger {
+	return slogLogger{l: l}
+}
+
+func (s slogLogger) Debug(msg string, kv ...interface{}) { s.l.Debug(msg, kv...) }
+func (s slogLogger) Info(msg string, kv ...interface{})  { s.l.Info(msg, kv...) }
+func (s slogLogger) Warn(msg string, kv ...interface{})  { s.l.Warn(msg, kv...) }
+func (s slogLogger) Error(msg string, kv ...interface{}) { s.l.Error(msg, kv...) }
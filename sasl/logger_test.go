@@ -0,0 +1,14 @@
+package sasl
+
+// recordingLogger captures the messages passed to Warn, for tests that
+// assert a particular warning was logged; other levels are discarded.
+type recordingLogger struct {
+	warnings []string
+}
+
+func (r *recordingLogger) Debug(string, ...interface{}) {}
+func (r *recordingLogger) Info(string, ...interface{})  {}
+func (r *recordingLogger) Warn(msg string, kv ...interface{}) {
+	r.warnings = append(r.warnings, msg)
+}
+func (r *recordingLogger) Error(string, ...interface{}) {}
@@ -0,0 +1,36 @@
+package sasl
+
+import "fmt"
+
+// plainClient implements the PLAIN mechanism (RFC 4616). It has no
+// security layer, so Wrap/Unwrap are identity functions.
+type plainClient struct {
+	username string
+	password string
+}
+
+func newPlainClient(opts *Options) *plainClient {
+	return &plainClient{username: opts.Username, password: opts.Password}
+}
+
+func (c *plainClient) Start(mechanisms []string) (string, []byte, bool, error) {
+	for _, m := range mechanisms {
+		if m == MechPlain {
+			initial := []byte("\x00" + c.username + "\x00" + c.password)
+			return MechPlain, initial, true, nil
+		}
+	}
+	return "", nil, false, fmt.Errorf("sasl: PLAIN not offered by %v", mechanisms)
+}
+
+func (c *plainClient) Step(challenge []byte) ([]byte, bool, error) {
+	return nil, true, fmt.Errorf("sasl: PLAIN does not expect a server challenge")
+}
+
+func (c *plainClient) QOP() QOP { return QOPAuth }
+
+func (c *plainClient) Wrap(plaintext []byte) ([]byte, error) { return plaintext, nil }
+
+func (c *plainClient) Unwrap(ciphertext []byte) ([]byte, error) { return ciphertext, nil }
+
+func (c *plainClient) Free() {}
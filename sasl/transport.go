@@ -3,7 +3,6 @@ package sasl
 import (
 	"bytes"
 	"context"
-	"encoding/binary"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -17,6 +16,9 @@ type TSaslTransport struct {
 
 	trans thrift.TTransport
 	sasl  Client
+	opts  *Options
+
+	qop QOP
 }
 
 // Status is SASL negotiation status
@@ -32,11 +34,19 @@ const (
 )
 
 func NewTSaslTransport(t thrift.TTransport, opts *Options) (*TSaslTransport, error) {
-	sasl := NewClient(opts)
+	if opts == nil {
+		opts = &Options{}
+	}
+	sasl, err := NewClient(opts)
+	if err != nil {
+		return nil, err
+	}
 
 	return &TSaslTransport{
 		trans: t,
 		sasl:  sasl,
+		opts:  opts,
+		qop:   QOPAuth,
 
 		rbuf: bytes.NewBuffer(nil),
 		wbuf: bytes.NewBuffer(nil),
@@ -55,43 +65,58 @@ func (t *TSaslTransport) Open() error {
 		}
 	}
 
-	mech, initial, _, err := t.sasl.Start([]string{MechPlain})
+	log := t.opts.logger()
+
+	mech, initial, _, err := t.sasl.Start(t.opts.mechanisms())
 	if err != nil {
+		log.Error("sasl: mechanism selection failed", "error", err)
 		return err
 	}
+	log.Info("sasl: negotiation started", "mechanism", mech)
 
 	if err := t.negotiationSend(StatusStart, []byte(mech)); err != nil {
+		log.Error("sasl: negotiation failed", "step", "start", "error", err)
 		return fmt.Errorf("sasl: negotiation failed. %v", err)
 	}
+	log.Debug("sasl: sent initial response", "bytes", len(initial))
 	if err := t.negotiationSend(StatusOK, initial); err != nil {
+		log.Error("sasl: negotiation failed", "step", "initial", "error", err)
 		return fmt.Errorf("sasl: negotiation failed. %v", err)
 	}
 
 	for {
 		status, challenge, err := t.recieve()
 		if err != nil {
+			log.Error("sasl: negotiation failed", "step", "receive", "error", err)
 			return fmt.Errorf("sasl: negotiation failed. %v", err)
 		}
+		log.Debug("sasl: received server frame", "status", status, "bytes", len(challenge))
 
 		if status != StatusOK && status != StatusComplete {
+			log.Error("sasl: negotiation failed", "status", status)
 			return fmt.Errorf("sasl: negotiation failed. bad status: %d", status)
 		}
 
 		if status == StatusComplete {
+			log.Info("sasl: negotiation complete", "mechanism", mech)
 			break
 		}
 
 		payload, _, err := t.sasl.Step(challenge)
 		if err != nil {
+			log.Error("sasl: negotiation failed", "step", "step", "error", err)
 			return fmt.Errorf("sasl: negotiation failed. %v", err)
 		}
 		if err := t.negotiationSend(StatusOK, payload); err != nil {
+			log.Error("sasl: negotiation failed", "step", "response", "error", err)
 			return fmt.Errorf("sasl: negotiation failed. %v", err)
 		}
 
 	}
-	return nil
 
+	t.qop = t.sasl.QOP()
+	log.Info("sasl: security layer established", "qop", t.qop)
+	return nil
 }
 
 func (t *TSaslTransport) Read(buf []byte) (int, error) {
@@ -106,19 +131,21 @@ func (t *TSaslTransport) Read(buf []byte) (int, error) {
 }
 
 func (t *TSaslTransport) readFrame(buf []byte) (int, error) {
-	header := make([]byte, 4)
-	_, err := t.trans.Read(header)
+	body, err := decodeDataFrame(t.trans, t.opts.maxFrameSize())
 	if err != nil {
 		return 0, err
 	}
 
-	l := binary.BigEndian.Uint32(header)
-
-	body := make([]byte, l)
-	_, err = io.ReadFull(t.trans, body)
-	if err != nil {
-		return 0, err
+	if t.qop != QOPAuth {
+		unwrapped, err := t.sasl.Unwrap(body)
+		if err != nil {
+			t.opts.logger().Error("sasl: unwrap failed", "error", err)
+			return 0, fmt.Errorf("sasl: unwrap failed. %v", err)
+		}
+		t.opts.logger().Debug("sasl: unwrapped frame", "wrapped_bytes", len(body), "plain_bytes", len(unwrapped))
+		body = unwrapped
 	}
+
 	t.rbuf = bytes.NewBuffer(body)
 	return t.rbuf.Read(buf)
 }
@@ -134,12 +161,19 @@ func (t *TSaslTransport) Flush(ctx context.Context) error {
 		return err
 	}
 
-	v := len(in)
-	var payload []byte
-	payload = append(payload, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
-	payload = append(payload, in...)
+	if t.qop != QOPAuth {
+		plainBytes := len(in)
+		in, err = t.sasl.Wrap(in)
+		if err != nil {
+			t.opts.logger().Error("sasl: wrap failed", "error", err)
+			return fmt.Errorf("sasl: wrap failed. %v", err)
+		}
+		t.opts.logger().Debug("sasl: wrapped frame", "plain_bytes", plainBytes, "wrapped_bytes", len(in))
+	}
 
-	t.trans.Write(payload)
+	if _, err := t.trans.Write(encodeDataFrame(in)); err != nil {
+		return err
+	}
 
 	t.wbuf.Reset()
 	return t.trans.Flush(ctx)
@@ -155,13 +189,7 @@ func (t *TSaslTransport) Close() error {
 }
 
 func (t *TSaslTransport) negotiationSend(status Status, body []byte) error {
-	var payload []byte
-	payload = append(payload, byte(status))
-	v := len(body)
-	payload = append(payload, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
-	payload = append(payload, body...)
-	_, err := t.trans.Write(payload)
-	if err != nil {
+	if _, err := t.trans.Write(encodeNegotiationFrame(status, body)); err != nil {
 		return err
 	}
 
@@ -173,10 +201,5 @@ func (t *TSaslTransport) negotiationSend(status Status, body []byte) error {
 }
 
 func (t *TSaslTransport) recieve() (Status, []byte, error) {
-	header := make([]byte, 5)
-	_, err := t.trans.Read(header)
-	if err != nil {
-		return 0, nil, err
-	}
-	return Status(header[0]), header[1:], nil
+	return decodeNegotiationFrame(t.trans, t.opts.maxFrameSize())
 }